@@ -0,0 +1,248 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	dbhelper "github.com/CodeClarityCE/utility-dbhelper/helper"
+	"github.com/lib/pq"
+	"github.com/uptrace/bun"
+)
+
+// notifyChannel is the PostgreSQL NOTIFY channel the Acquirer subscribes to.
+// Rows in the `analysis` table fire a notification on this channel whenever
+// their schedule changes, see installNotifyTriggers.
+const notifyChannel = "analysis_scheduled"
+
+// fullSweepInterval is the safety-net interval at which the Acquirer runs a
+// full due-analysis query even if no NOTIFY ever arrives, in case the LISTEN
+// connection silently drops.
+const fullSweepInterval = 5 * time.Minute
+
+// dueEntry is a single upcoming scheduled run tracked by the Acquirer's heap.
+type dueEntry struct {
+	id   string
+	next time.Time
+}
+
+// dueHeap is a min-heap of dueEntry ordered by next run time, so the
+// Acquirer always knows the soonest due row without re-querying the
+// database on every wakeup.
+type dueHeap []dueEntry
+
+func (h dueHeap) Len() int            { return len(h) }
+func (h dueHeap) Less(i, j int) bool  { return h[i].next.Before(h[j].next) }
+func (h dueHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *dueHeap) Push(x interface{}) { *h = append(*h, x.(dueEntry)) }
+func (h *dueHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// Acquirer replaces fixed-interval polling with an event-driven wakeup
+// scheme: it listens for PostgreSQL NOTIFY events on notifyChannel, keeps a
+// min-heap of upcoming `next_scheduled_run` times so it can set a single
+// timer for the soonest due row, and falls back to a periodic full sweep if
+// the LISTEN connection is ever lost.
+type Acquirer struct {
+	service  *SchedulerService
+	dsn      string
+	listener *pq.Listener
+
+	upcoming dueHeap
+	wake     chan struct{}
+}
+
+// NewAcquirer creates an Acquirer bound to the given scheduler service. dsn
+// is used to open the dedicated LISTEN connection; bun's pooled connection
+// cannot be used for this since it needs to block waiting for notifications.
+func NewAcquirer(service *SchedulerService, dsn string) *Acquirer {
+	return &Acquirer{
+		service: service,
+		dsn:     dsn,
+		// buffered by one: repeated requestWake calls coalesce into a
+		// single pending wakeup instead of queueing one per call.
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Start opens the LISTEN connection and begins listening for wakeups in the
+// background; the NOTIFY triggers themselves are installed separately by
+// installNotifyTriggers. It returns once the listener is established;
+// the actual event loop runs in a goroutine until ctx is cancelled.
+func (a *Acquirer) Start(ctx context.Context) error {
+	eventCallback := func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Acquirer: listener event %v: %v", ev, err)
+		}
+	}
+
+	a.listener = pq.NewListener(a.dsn, 10*time.Second, time.Minute, eventCallback)
+	if err := a.listener.Listen(notifyChannel); err != nil {
+		a.listener.Close()
+		return fmt.Errorf("failed to listen on %q: %w", notifyChannel, err)
+	}
+
+	go a.run(ctx)
+	return nil
+}
+
+// requestWake schedules an immediate re-check of due analyses; the buffered
+// channel means repeated calls before the loop runs coalesce into one query.
+// The NOTIFY path in run does not go through it: bursts of notifications are
+// coalesced by drainBurst.
+func (a *Acquirer) requestWake() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run is the Acquirer's event loop: it wakes on a NOTIFY, on the timer for
+// the earliest known due row, or on the full-sweep safety net, and then
+// re-runs processDueAnalyses and refreshes the upcoming heap. A nil
+// notification (connection reset) only re-arms the timer and waits again.
+func (a *Acquirer) run(ctx context.Context) {
+	defer a.listener.Close()
+
+	sweep := time.NewTicker(fullSweepInterval)
+	defer sweep.Stop()
+
+	a.refresh(ctx)
+
+	for {
+		timer := a.timerForNextDue()
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case n := <-a.listener.Notify:
+			timer.Stop()
+			if n == nil {
+				// nil notification means the connection was lost and
+				// reconnected; the periodic sweep covers us either way.
+				log.Println("Acquirer: LISTEN connection reset")
+				continue
+			}
+			a.drainBurst()
+		case <-a.wake:
+			timer.Stop()
+		case <-timer.C:
+		case <-sweep.C:
+			timer.Stop()
+		}
+
+		a.service.processDueAnalyses()
+		a.refresh(ctx)
+	}
+}
+
+// drainBurst swallows any further notifications already queued up behind
+// the one that just woke the loop, so a burst of NOTIFYs results in one
+// processDueAnalyses call rather than one per event.
+func (a *Acquirer) drainBurst() {
+	for {
+		select {
+		case <-a.listener.Notify:
+		default:
+			return
+		}
+	}
+}
+
+// timerForNextDue returns a timer firing at the soonest known upcoming run,
+// or one firing after fullSweepInterval if the heap is empty (so the loop
+// waits on the sweep/wake channels without busy-looping).
+func (a *Acquirer) timerForNextDue() *time.Timer {
+	if len(a.upcoming) == 0 {
+		return time.NewTimer(fullSweepInterval)
+	}
+	d := time.Until(a.upcoming[0].next)
+	if d < 0 {
+		d = 0
+	}
+	return time.NewTimer(d)
+}
+
+// refresh re-populates the upcoming heap from the database, keeping the
+// soonest rows regardless of whether they came due via NOTIFY or sweep.
+func (a *Acquirer) refresh(ctx context.Context) {
+	var rows []ScheduledAnalysis
+	err := a.service.DB.CodeClarity.NewSelect().
+		Model(&rows).
+		Column("id", "next_scheduled_run").
+		Where("is_active = ?", true).
+		Where("next_scheduled_run IS NOT NULL").
+		OrderExpr("next_scheduled_run ASC").
+		Limit(128).
+		Scan(ctx)
+	if err != nil {
+		log.Printf("Acquirer: failed to refresh upcoming heap: %v", err)
+		return
+	}
+
+	h := make(dueHeap, 0, len(rows))
+	for _, row := range rows {
+		h = append(h, dueEntry{id: row.ID, next: *row.NextScheduledRun})
+	}
+	heap.Init(&h)
+	a.upcoming = h
+}
+
+// Close shuts down the Acquirer's LISTEN connection.
+func (a *Acquirer) Close() error {
+	if a.listener == nil {
+		return nil
+	}
+	return a.listener.Close()
+}
+
+// installNotifyTriggers creates the pg_notify trigger on the analysis table
+// so inserts and schedule updates wake the Acquirer without requiring any
+// changes in the API layer.
+func installNotifyTriggers(ctx context.Context, db *bun.DB) error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION notify_analysis_scheduled() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('` + notifyChannel + `', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS analysis_scheduled_notify ON analysis`,
+		`CREATE TRIGGER analysis_scheduled_notify
+AFTER INSERT OR UPDATE OF next_scheduled_run, is_active, schedule_type ON analysis
+FOR EACH ROW EXECUTE FUNCTION notify_analysis_scheduled()`,
+	}
+
+	for _, stmt := range statements {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to install analysis notify trigger: %w", err)
+		}
+	}
+	return nil
+}
+
+// buildListenDSN assembles the connection string used for the dedicated
+// LISTEN connection, mirroring the env vars the rest of the service is
+// configured from.
+func buildListenDSN() (string, error) {
+	host := os.Getenv("PG_DB_HOST")
+	port := os.Getenv("PG_DB_PORT")
+	user := os.Getenv("PG_DB_USER")
+	password := os.Getenv("PG_DB_PASSWORD")
+
+	if host == "" || port == "" || user == "" || password == "" {
+		return "", fmt.Errorf("PG_DB_HOST, PG_DB_PORT, PG_DB_USER and PG_DB_PASSWORD must be set")
+	}
+
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+		user, password, host, port, dbhelper.Config.Database.Results), nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"container/heap"
+	"testing"
+	"time"
+)
+
+func TestDueHeapOrdersBySoonestFirst(t *testing.T) {
+	now := time.Now()
+
+	h := &dueHeap{
+		{id: "c", next: now.Add(3 * time.Hour)},
+		{id: "a", next: now.Add(1 * time.Hour)},
+		{id: "b", next: now.Add(2 * time.Hour)},
+	}
+	heap.Init(h)
+
+	var order []string
+	for h.Len() > 0 {
+		entry := heap.Pop(h).(dueEntry)
+		order = append(order, entry.id)
+	}
+
+	want := []string{"a", "b", "c"}
+	for i, id := range want {
+		if order[i] != id {
+			t.Fatalf("expected pop order %v, got %v", want, order)
+		}
+	}
+}
+
+func TestDueHeapPushMaintainsOrder(t *testing.T) {
+	now := time.Now()
+
+	h := &dueHeap{}
+	heap.Init(h)
+	heap.Push(h, dueEntry{id: "later", next: now.Add(time.Hour)})
+	heap.Push(h, dueEntry{id: "sooner", next: now.Add(time.Minute)})
+
+	top := (*h)[0]
+	if top.id != "sooner" {
+		t.Fatalf("expected %q at the top of the heap, got %q", "sooner", top.id)
+	}
+}
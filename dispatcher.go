@@ -0,0 +1,443 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Attempt statuses recorded in scheduled_run_attempts.
+const (
+	AttemptStatusSuccess    = "success"
+	AttemptStatusFailed     = "failed"
+	AttemptStatusDeadLetter = "dead_letter"
+)
+
+// defaultMaxAttempts is how many consecutive failures a scheduled analysis
+// may accumulate before it is moved to dead_letter status.
+const defaultMaxAttempts = 5
+
+// defaultBaseBackoff and defaultMaxBackoff bound the exponential backoff
+// applied between retries.
+const (
+	defaultBaseBackoff = time.Minute
+	defaultMaxBackoff  = 30 * time.Minute
+)
+
+// ScheduledRunAttempt records a single dispatch attempt for a scheduled
+// analysis: whether it succeeded, how long it took, and why it failed if it
+// didn't. This replaces the old fire-and-forget behaviour where a failed
+// API call or RMQ publish simply vanished until the next minute's sweep.
+type ScheduledRunAttempt struct {
+	bun.BaseModel `bun:"table:scheduled_run_attempts"`
+	ID            string    `bun:"id,pk"`
+	AnalysisID    string    `bun:"analysis_id"`
+	AttemptNo     int       `bun:"attempt_no"`
+	Status        string    `bun:"status"`
+	Error         *string   `bun:"error"`
+	DurationMs    int64     `bun:"duration_ms"`
+	CreatedOn     time.Time `bun:"created_on"`
+}
+
+func (ScheduledRunAttempt) TableName() string {
+	return "scheduled_run_attempts"
+}
+
+// RunDispatcher wraps dispatch of a due analysis with durable attempt
+// tracking, exponential backoff with jitter, and dead-lettering once
+// maxAttempts is exhausted.
+type RunDispatcher struct {
+	service     *SchedulerService
+	maxAttempts int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+// NewRunDispatcher creates a RunDispatcher for the given service, reading
+// SCHEDULER_MAX_ATTEMPTS from the environment (defaulting to
+// defaultMaxAttempts) so operators can tune retry tolerance per deployment.
+func NewRunDispatcher(service *SchedulerService) *RunDispatcher {
+	maxAttempts := defaultMaxAttempts
+	if v := os.Getenv("SCHEDULER_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			maxAttempts = n
+		}
+	}
+
+	return &RunDispatcher{
+		service:     service,
+		maxAttempts: maxAttempts,
+		baseBackoff: defaultBaseBackoff,
+		maxBackoff:  defaultMaxBackoff,
+	}
+}
+
+// Dispatch runs a single due analysis under a per-analysis advisory lock,
+// as defense-in-depth alongside the replica-wide LeaderElector: even during
+// a brief split-brain window, two replicas can't dispatch the same analysis
+// concurrently.
+func (d *RunDispatcher) Dispatch(ctx context.Context, analysis ScheduledAnalysis) {
+	d.withAnalysisLock(ctx, analysis.ID, func() {
+		d.dispatchLocked(ctx, analysis)
+	})
+}
+
+// analysisLockNamespace is the first key of the two-integer advisory lock
+// form used for per-analysis locks (see withAnalysisLock). Keeping it
+// distinct from schedulerLeaderLockKey puts row locks in their own 64-bit
+// keyspace so a hashtext collision on an analysis ID can never be confused
+// with the replica-wide leader lock.
+const analysisLockNamespace = 483920171
+
+// withAnalysisLock holds a PostgreSQL advisory lock keyed on the analysis
+// ID for the duration of fn. If the lock is already held by another
+// replica, fn is skipped entirely rather than racing it.
+func (d *RunDispatcher) withAnalysisLock(ctx context.Context, analysisID string, fn func()) {
+	conn, err := d.service.DB.CodeClarity.Conn(ctx)
+	if err != nil {
+		log.Printf("RunDispatcher: failed to open lock connection for %s, proceeding unlocked: %v", analysisID, err)
+		fn()
+		return
+	}
+	defer conn.Close()
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, hashtext($2))", analysisLockNamespace, analysisID).Scan(&acquired); err != nil {
+		log.Printf("RunDispatcher: failed to acquire row lock for %s, proceeding unlocked: %v", analysisID, err)
+		fn()
+		return
+	}
+	if !acquired {
+		log.Printf("RunDispatcher: analysis %s is locked by another replica, skipping", analysisID)
+		return
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1, hashtext($2))", analysisLockNamespace, analysisID)
+
+	fn()
+}
+
+// dispatchLocked is Dispatch's actual body, run while holding the
+// per-analysis advisory lock.
+func (d *RunDispatcher) dispatchLocked(ctx context.Context, analysis ScheduledAnalysis) {
+	ctx, span := tracer.Start(ctx, "scheduler.dispatch")
+	defer span.End()
+
+	attemptNo := d.nextAttemptNumber(ctx, analysis.ID)
+	start := time.Now()
+
+	// If a previous attempt already created the analysis execution but
+	// failed to publish it, reuse that execution instead of creating a
+	// second, orphaned one - createAnalysisExecution is not idempotent, so
+	// calling it again on every retry would leak one execution per failed
+	// attempt.
+	newAnalysisId := ""
+	if analysis.PendingExecutionID != nil {
+		newAnalysisId = *analysis.PendingExecutionID
+	}
+
+	var err error
+	if newAnalysisId == "" {
+		newAnalysisId, err = d.service.createAnalysisExecution(ctx, analysis)
+		if err == nil {
+			if saveErr := d.savePendingExecution(ctx, analysis.ID, newAnalysisId); saveErr != nil {
+				log.Printf("RunDispatcher: failed to persist pending execution %s for %s: %v", newAnalysisId, analysis.ID, saveErr)
+			}
+		}
+	}
+
+	if err == nil {
+		err = d.service.sendAnalysisMessage(ctx, analysis, newAnalysisId)
+	}
+	duration := time.Since(start)
+	dispatchDuration.Observe(duration.Seconds())
+
+	if err != nil {
+		span.RecordError(err)
+
+		if d.attemptsExhausted(attemptNo) {
+			d.recordAttempt(ctx, analysis.ID, attemptNo, AttemptStatusDeadLetter, err, duration)
+			runsDispatchedTotal.WithLabelValues(AttemptStatusDeadLetter).Inc()
+			d.deadLetter(ctx, analysis, err)
+			return
+		}
+
+		d.recordAttempt(ctx, analysis.ID, attemptNo, AttemptStatusFailed, err, duration)
+		runsDispatchedTotal.WithLabelValues(AttemptStatusFailed).Inc()
+		d.scheduleRetry(ctx, analysis, attemptNo)
+		return
+	}
+
+	runsDispatchedTotal.WithLabelValues(AttemptStatusSuccess).Inc()
+	d.recordAttempt(ctx, analysis.ID, attemptNo, AttemptStatusSuccess, nil, duration)
+	d.clearPendingExecution(ctx, analysis.ID)
+	d.advanceSchedule(ctx, analysis, start)
+}
+
+// savePendingExecution records the analysis execution id created for this
+// dispatch attempt so a retry that only needs to resend the RMQ message
+// doesn't call createAnalysisExecution again.
+func (d *RunDispatcher) savePendingExecution(ctx context.Context, analysisID, executionID string) error {
+	_, err := d.service.DB.CodeClarity.NewUpdate().
+		Model((*ScheduledAnalysis)(nil)).
+		Set("pending_execution_id = ?", executionID).
+		Where("id = ?", analysisID).
+		Exec(ctx)
+	return err
+}
+
+// clearPendingExecution removes the pending execution marker once it has
+// been successfully published or the row has been dead-lettered, so a
+// future retry (or a requeue) starts a fresh execution instead of reusing
+// a stale id.
+func (d *RunDispatcher) clearPendingExecution(ctx context.Context, analysisID string) {
+	_, err := d.service.DB.CodeClarity.NewUpdate().
+		Model((*ScheduledAnalysis)(nil)).
+		Set("pending_execution_id = ?", nil).
+		Where("id = ?", analysisID).
+		Exec(ctx)
+	if err != nil {
+		log.Printf("RunDispatcher: failed to clear pending execution for %s: %v", analysisID, err)
+	}
+}
+
+// nextAttemptNumber counts failed attempts since the last success or
+// dead-letter for this analysis, so a row that has been failing gets an
+// increasing attempt number while one that just recovered starts back at 1.
+func (d *RunDispatcher) nextAttemptNumber(ctx context.Context, analysisID string) int {
+	count, err := d.service.DB.CodeClarity.NewSelect().
+		Model((*ScheduledRunAttempt)(nil)).
+		Where("analysis_id = ?", analysisID).
+		Where("status = ?", AttemptStatusFailed).
+		Where("created_on > (SELECT COALESCE(MAX(created_on), 'epoch') FROM scheduled_run_attempts WHERE analysis_id = ? AND status != ?)", analysisID, AttemptStatusFailed).
+		Count(ctx)
+	if err != nil {
+		log.Printf("RunDispatcher: failed to count prior attempts for %s: %v", analysisID, err)
+		return 1
+	}
+	return count + 1
+}
+
+func (d *RunDispatcher) recordAttempt(ctx context.Context, analysisID string, attemptNo int, status string, dispatchErr error, duration time.Duration) {
+	attempt := &ScheduledRunAttempt{
+		ID:         uuid.NewString(),
+		AnalysisID: analysisID,
+		AttemptNo:  attemptNo,
+		Status:     status,
+		DurationMs: duration.Milliseconds(),
+		CreatedOn:  time.Now(),
+	}
+	if dispatchErr != nil {
+		msg := dispatchErr.Error()
+		attempt.Error = &msg
+	}
+
+	if _, err := d.service.DB.CodeClarity.NewInsert().Model(attempt).Exec(ctx); err != nil {
+		log.Printf("RunDispatcher: failed to record attempt for %s: %v", analysisID, err)
+	}
+}
+
+// attemptsExhausted reports whether attemptNo has used up the retry budget
+// and the analysis should be dead-lettered instead of retried again.
+func (d *RunDispatcher) attemptsExhausted(attemptNo int) bool {
+	return attemptNo >= d.maxAttempts
+}
+
+// backoffDuration computes the exponential backoff (capped at max) for the
+// given attempt number, with jitter so a burst of simultaneously failing
+// analyses doesn't retry in lockstep: the result is drawn from
+// [backoff/2, backoff), so it never exceeds max. attemptNo is 1-indexed: the
+// first failure backs off by up to base, the second by up to 2*base, and so on.
+func backoffDuration(attemptNo int, base, max time.Duration) time.Duration {
+	backoff := time.Duration(float64(base) * math.Pow(2, float64(attemptNo-1)))
+	if backoff > max {
+		backoff = max
+	}
+	jitter := time.Duration(rand.Int63n(int64(backoff) / 2))
+	return backoff/2 + jitter
+}
+
+// scheduleRetry backs off exponentially (with jitter, capped at maxBackoff)
+// by moving the failed row's past-due next_scheduled_run to now plus the
+// backoff, so the Acquirer does not pick it up again until then.
+func (d *RunDispatcher) scheduleRetry(ctx context.Context, analysis ScheduledAnalysis, attemptNo int) {
+	retryAt := time.Now().Add(backoffDuration(attemptNo, d.baseBackoff, d.maxBackoff))
+
+	_, err := d.service.DB.CodeClarity.NewUpdate().
+		Model((*ScheduledAnalysis)(nil)).
+		Set("next_scheduled_run = ?", retryAt).
+		Where("id = ?", analysis.ID).
+		Exec(ctx)
+	if err != nil {
+		log.Printf("RunDispatcher: failed to schedule retry for %s: %v", analysis.ID, err)
+		return
+	}
+
+	log.Printf("RunDispatcher: attempt %d/%d for %s failed, retrying at %s", attemptNo, d.maxAttempts, analysis.ID, retryAt.Format(time.RFC3339))
+}
+
+// deadLetter marks an analysis as exhausted so it stops being picked up by
+// processDueAnalyses until an operator re-queues it.
+func (d *RunDispatcher) deadLetter(ctx context.Context, analysis ScheduledAnalysis, lastErr error) {
+	reason := fmt.Sprintf("exhausted %d attempts: %v", d.maxAttempts, lastErr)
+	now := time.Now()
+
+	_, err := d.service.DB.CodeClarity.NewUpdate().
+		Model((*ScheduledAnalysis)(nil)).
+		Set("is_active = ?", false).
+		Set("dead_letter_reason = ?", reason).
+		Set("dead_lettered_at = ?", now).
+		Where("id = ?", analysis.ID).
+		Exec(ctx)
+	if err != nil {
+		log.Printf("RunDispatcher: failed to dead-letter %s: %v", analysis.ID, err)
+		return
+	}
+
+	// Don't carry a pending execution id into the dead-lettered state - a
+	// future requeue should dispatch fresh rather than assuming a
+	// long-stale execution is still the right one to publish.
+	d.clearPendingExecution(ctx, analysis.ID)
+
+	log.Printf("RunDispatcher: dead-lettered analysis %s: %s", analysis.ID, reason)
+}
+
+// advanceSchedule records the successful run and computes the next one, as
+// processAnalysis used to do before dispatch was extracted; unlike before, a
+// schedule whose next run cannot be computed dead-letters the row.
+func (d *RunDispatcher) advanceSchedule(ctx context.Context, analysis ScheduledAnalysis, now time.Time) {
+	nextRun, err := calculateNextRun(analysis, now)
+	if err != nil {
+		// The dispatch itself succeeded, but the row's schedule config
+		// (cron expression / timezone) is permanently broken. Leaving
+		// next_scheduled_run untouched would keep this row "due" forever,
+		// and the NOTIFY-driven Acquirer reacts to that almost instantly -
+		// so instead of retrying a deterministic failure, dead-letter it
+		// now rather than dispatching it again on every wakeup.
+		d.deadLetter(ctx, analysis, fmt.Errorf("invalid schedule configuration: %w", err))
+		return
+	}
+
+	_, err = d.service.DB.CodeClarity.NewUpdate().
+		Model((*ScheduledAnalysis)(nil)).
+		Set("last_scheduled_run = ?", now).
+		Set("next_scheduled_run = ?", nextRun).
+		Where("id = ?", analysis.ID).
+		Exec(ctx)
+	if err != nil {
+		log.Printf("RunDispatcher: failed to update analysis schedule for %s: %v", analysis.ID, err)
+		return
+	}
+
+	log.Printf("RunDispatcher: successfully processed analysis %s, next run: %s", analysis.ID, nextRun.Format(time.RFC3339))
+}
+
+// ListenHTTP serves the attempt-visibility API: listing recent attempts,
+// manually re-queueing a dead-lettered run, and validating a schedule before
+// it's written by whichever service owns analysis creation.
+func (d *RunDispatcher) ListenHTTP(addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/attempts", d.handleListAttempts)
+	mux.HandleFunc("/attempts/requeue/", d.handleRequeue)
+	mux.HandleFunc("/schedule/validate", d.handleValidateSchedule)
+
+	log.Printf("RunDispatcher: serving attempt API on %s", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+func (d *RunDispatcher) handleListAttempts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var attempts []ScheduledRunAttempt
+	err := d.service.DB.CodeClarity.NewSelect().
+		Model(&attempts).
+		OrderExpr("created_on DESC").
+		Limit(200).
+		Scan(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list attempts: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attempts)
+}
+
+func (d *RunDispatcher) handleRequeue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	analysisID := r.URL.Path[len("/attempts/requeue/"):]
+	if analysisID == "" {
+		http.Error(w, "missing analysis id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+
+	analysis := ScheduledAnalysis{ID: analysisID}
+	if err := d.service.DB.CodeClarity.NewSelect().Model(&analysis).Where("id = ?", analysisID).Scan(ctx); err != nil {
+		http.Error(w, fmt.Sprintf("failed to load %s: %v", analysisID, err), http.StatusNotFound)
+		return
+	}
+
+	// Refuse to resurrect a row whose schedule is still broken - it would
+	// just dispatch once and immediately dead-letter again.
+	if err := ValidateSchedule(analysis); err != nil {
+		http.Error(w, fmt.Sprintf("refusing to requeue %s, schedule is still invalid: %v", analysisID, err), http.StatusUnprocessableEntity)
+		return
+	}
+
+	_, err := d.service.DB.CodeClarity.NewUpdate().
+		Model((*ScheduledAnalysis)(nil)).
+		Set("is_active = ?", true).
+		Set("dead_letter_reason = ?", nil).
+		Set("dead_lettered_at = ?", nil).
+		Set("pending_execution_id = ?", nil).
+		Set("next_scheduled_run = ?", time.Now()).
+		Where("id = ?", analysisID).
+		Exec(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to requeue %s: %v", analysisID, err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleValidateSchedule lets the service that owns analysis creation (this
+// scheduler only ever reads rows written elsewhere) check a cron
+// expression/timezone/schedule_type combination before writing it, instead
+// of finding out it's broken only once this service dispatches and
+// dead-letters it. It validates the same fields ValidateSchedule checks,
+// decoded from the request body rather than loaded from the database.
+func (d *RunDispatcher) handleValidateSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var analysis ScheduledAnalysis
+	if err := json.NewDecoder(r.Body).Decode(&analysis); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := ValidateSchedule(analysis); err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
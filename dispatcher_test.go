@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoffDurationCapsAtMax(t *testing.T) {
+	base := time.Second
+	max := 10 * time.Second
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := backoffDuration(attempt, base, max)
+		if d < 0 {
+			t.Fatalf("attempt %d: backoff must not be negative, got %s", attempt, d)
+		}
+		if d > max {
+			t.Fatalf("attempt %d: backoff %s exceeds max %s", attempt, d, max)
+		}
+	}
+}
+
+func TestBackoffDurationGrowsWithAttempt(t *testing.T) {
+	base := time.Second
+	max := time.Hour
+
+	// attempt 1's un-jittered backoff is base*2^0 = base, so the jittered
+	// result falls in [base/2, base). attempt 5's is base*2^4 = 16*base, so
+	// its result falls in [8*base, 16*base) - well clear of attempt 1's
+	// range regardless of jitter.
+	d1 := backoffDuration(1, base, max)
+	d5 := backoffDuration(5, base, max)
+
+	if d1 >= 8*base {
+		t.Fatalf("attempt 1 backoff %s unexpectedly large for base %s", d1, base)
+	}
+	if d5 < 8*base {
+		t.Fatalf("attempt 5 backoff %s should be at least 8x base %s", d5, base)
+	}
+}
+
+func TestAttemptsExhaustedDeadLetterThreshold(t *testing.T) {
+	dispatcher := &RunDispatcher{maxAttempts: 3}
+
+	cases := []struct {
+		attemptNo        int
+		shouldDeadLetter bool
+	}{
+		{1, false},
+		{2, false},
+		{3, true},
+		{4, true},
+	}
+
+	for _, c := range cases {
+		got := dispatcher.attemptsExhausted(c.attemptNo)
+		if got != c.shouldDeadLetter {
+			t.Errorf("attempt %d: expected dead-letter=%v, got %v", c.attemptNo, c.shouldDeadLetter, got)
+		}
+	}
+}
+
+func TestHandleValidateScheduleRejectsInvalidBody(t *testing.T) {
+	dispatcher := &RunDispatcher{}
+
+	cases := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"valid schedule", http.MethodPost, `{"ScheduleType":"daily"}`, http.StatusNoContent},
+		{"invalid cron expression", http.MethodPost, `{"CronExpression":"not a cron"}`, http.StatusUnprocessableEntity},
+		{"malformed json", http.MethodPost, `not json`, http.StatusBadRequest},
+		{"wrong method", http.MethodGet, `{"ScheduleType":"daily"}`, http.StatusMethodNotAllowed},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, "/schedule/validate", strings.NewReader(c.body))
+			rec := httptest.NewRecorder()
+
+			dispatcher.handleValidateSchedule(rec, req)
+
+			if rec.Code != c.wantStatus {
+				t.Errorf("expected status %d, got %d", c.wantStatus, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// schedulerLeaderLockKey is the pg_advisory_lock key all scheduler replicas
+// contend for; only the replica holding it is allowed to dispatch analyses.
+const schedulerLeaderLockKey = 847362910
+
+// leaderPollInterval is how often a standby replica retries the advisory
+// lock, and how often the leader checks that its lease connection is still
+// alive.
+const leaderPollInterval = 5 * time.Second
+
+// LeaderElector ensures only one scheduler replica is active at a time, so
+// that running multiple replicas doesn't double-dispatch the same due
+// analyses. It uses a PostgreSQL session-level advisory lock: the lock is
+// held for as long as the underlying connection stays open, so a crashed or
+// partitioned leader releases it automatically without needing an explicit
+// lease-expiry protocol.
+type LeaderElector struct {
+	service   *SchedulerService
+	onElected func(ctx context.Context)
+	onDemoted func()
+
+	mu         sync.RWMutex
+	leader     bool
+	conn       *bun.Conn
+	termCancel context.CancelFunc
+}
+
+// NewLeaderElector creates a LeaderElector for the given service. onElected
+// is called (in its own goroutine) when this replica becomes leader;
+// onDemoted is called when it steps down, either voluntarily or because its
+// lease connection died.
+func NewLeaderElector(service *SchedulerService, onElected func(ctx context.Context), onDemoted func()) *LeaderElector {
+	return &LeaderElector{
+		service:   service,
+		onElected: onElected,
+		onDemoted: onDemoted,
+	}
+}
+
+// IsLeader reports whether this replica currently holds the lock.
+func (e *LeaderElector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.leader
+}
+
+// Run drives the election loop until ctx is cancelled: standby replicas
+// retry the advisory lock every leaderPollInterval, while the current
+// leader uses the same tick to verify its lease connection is still alive.
+func (e *LeaderElector) Run(ctx context.Context) {
+	for {
+		if e.IsLeader() {
+			e.heartbeat(ctx)
+		} else {
+			e.tryAcquire(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			if e.IsLeader() {
+				e.stepDown()
+			}
+			return
+		case <-time.After(leaderPollInterval):
+		}
+	}
+}
+
+func (e *LeaderElector) tryAcquire(ctx context.Context) {
+	conn, err := e.service.DB.CodeClarity.Conn(ctx)
+	if err != nil {
+		log.Printf("LeaderElector: failed to open election connection: %v", err)
+		return
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", schedulerLeaderLockKey).Scan(&acquired); err != nil {
+		log.Printf("LeaderElector: failed to try advisory lock: %v", err)
+		conn.Close()
+		return
+	}
+	if !acquired {
+		conn.Close()
+		return
+	}
+
+	// termCtx scopes everything onElected starts (the Acquirer's goroutine
+	// included) to this leadership term: cancelling it on stepDown is what
+	// actually stops that goroutine, rather than relying on the shared,
+	// never-cancelled process-lifetime context.
+	termCtx, cancel := context.WithCancel(ctx)
+
+	e.mu.Lock()
+	e.leader = true
+	e.conn = &conn
+	e.termCancel = cancel
+	e.mu.Unlock()
+
+	log.Println("LeaderElector: acquired leadership")
+	go e.onElected(termCtx)
+}
+
+// heartbeat verifies the lease connection is still usable. If it isn't
+// (network partition, dropped connection, ...) Postgres has already
+// released the advisory lock on its end, so we step down to match.
+func (e *LeaderElector) heartbeat(ctx context.Context) {
+	e.mu.RLock()
+	conn := e.conn
+	e.mu.RUnlock()
+
+	if conn == nil {
+		return
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT 1"); err != nil {
+		log.Printf("LeaderElector: lease connection died, stepping down: %v", err)
+		e.stepDown()
+	}
+}
+
+func (e *LeaderElector) stepDown() {
+	e.mu.Lock()
+	conn := e.conn
+	cancel := e.termCancel
+	e.leader = false
+	e.conn = nil
+	e.termCancel = nil
+	e.mu.Unlock()
+
+	// Cancel the term context first so anything onElected started (the
+	// Acquirer's run loop) observes ctx.Done() instead of spinning on a
+	// now-closed listener channel.
+	if cancel != nil {
+		cancel()
+	}
+	if conn != nil {
+		conn.Close()
+	}
+	if e.onDemoted != nil {
+		e.onDemoted()
+	}
+}
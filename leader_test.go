@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStepDownCancelsTermContextAndDemotes(t *testing.T) {
+	termCtx, cancel := context.WithCancel(context.Background())
+
+	demoted := false
+	e := &LeaderElector{
+		leader:     true,
+		termCancel: cancel,
+		onDemoted:  func() { demoted = true },
+	}
+
+	e.stepDown()
+
+	if e.IsLeader() {
+		t.Error("expected leader to be false after stepDown")
+	}
+	if termCtx.Err() == nil {
+		t.Error("expected the term context to be cancelled after stepDown")
+	}
+	if !demoted {
+		t.Error("expected onDemoted to be called after stepDown")
+	}
+}
+
+func TestStepDownWithoutTermCancelStillDemotes(t *testing.T) {
+	demoted := false
+	e := &LeaderElector{
+		leader:    true,
+		onDemoted: func() { demoted = true },
+	}
+
+	e.stepDown()
+
+	if e.IsLeader() {
+		t.Error("expected leader to be false after stepDown")
+	}
+	if !demoted {
+		t.Error("expected onDemoted to be called after stepDown")
+	}
+}
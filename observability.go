@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// tracer is shared by every scheduler component that opens a span; see
+// processDueAnalyses, processAnalysis and RunDispatcher.Dispatch.
+var tracer = otel.Tracer("service-scheduler")
+
+// Prometheus metrics, as requested: a counter for dispatch outcomes, two
+// histograms for dispatch latency and scheduling lag, and gauges describing
+// the current schedule population.
+var (
+	runsDispatchedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "scheduler_runs_dispatched_total",
+		Help: "Scheduled analysis dispatch attempts, labeled by outcome (success, failed, dead_letter).",
+	}, []string{"status"})
+
+	dispatchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "scheduler_dispatch_duration_seconds",
+		Help:    "Time to create the analysis execution via the API and publish its RMQ message.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	dispatchLag = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "scheduler_lag_seconds",
+		Help:    "now() minus next_scheduled_run at the moment an analysis was dispatched.",
+		Buckets: prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	activeSchedules = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "scheduler_active_schedules",
+		Help: "Number of scheduled analyses currently active.",
+	})
+
+	dueBacklog = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "scheduler_due_backlog",
+		Help: "Number of active scheduled analyses past their next_scheduled_run as of the last tick.",
+	})
+)
+
+// initTracing wires up the global TracerProvider and W3C trace-context
+// propagator. If OTEL_EXPORTER_OTLP_ENDPOINT isn't set, spans are still
+// created (tracer.Start never returns a nil span) but go nowhere, which
+// keeps the instrumentation harmless in environments without a collector.
+func initTracing(ctx context.Context) (func(context.Context) error, error) {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	if endpoint == "" {
+		log.Println("OTEL_EXPORTER_OTLP_ENDPOINT not set, tracing spans will not be exported")
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpoint(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName("service-scheduler")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tp.Shutdown, nil
+}
+
+// serveMetrics exposes the Prometheus scrape endpoint.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	log.Printf("Serving metrics on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("Metrics server stopped: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestMetricsEndpointExposesRegisteredMetrics(t *testing.T) {
+	runsDispatchedTotal.WithLabelValues(AttemptStatusSuccess).Inc()
+	activeSchedules.Set(3)
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rec := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	for _, name := range []string{
+		"scheduler_runs_dispatched_total",
+		"scheduler_dispatch_duration_seconds",
+		"scheduler_lag_seconds",
+		"scheduler_active_schedules",
+		"scheduler_due_backlog",
+	} {
+		if !strings.Contains(body, name) {
+			t.Errorf("expected /metrics output to contain %q", name)
+		}
+	}
+}
+
+func TestInitTracingWithoutEndpointIsHarmless(t *testing.T) {
+	os.Unsetenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+
+	shutdown, err := initTracing(context.Background())
+	if err != nil {
+		t.Fatalf("expected no error with no OTLP endpoint configured, got %v", err)
+	}
+	if shutdown == nil {
+		t.Fatal("expected a non-nil shutdown func even without a collector")
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("expected shutdown to be a no-op, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser accepts both standard 5-field (minute hour dom month dow) and
+// 6-field (with a leading seconds field) cron expressions, plus the usual
+// @daily/@hourly descriptors.
+var cronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// calculateNextRun computes the next time a scheduled analysis should fire,
+// in the analysis' configured IANA timezone (UTC if unset). A
+// CronExpression takes precedence over ScheduleType when both are set;
+// otherwise ScheduleType is matched against the well-known schedule
+// keywords, including the ad-hoc "interval:<duration>" form.
+func calculateNextRun(analysis ScheduledAnalysis, from time.Time) (time.Time, error) {
+	loc := time.UTC
+	if analysis.Timezone != nil && *analysis.Timezone != "" {
+		l, err := time.LoadLocation(*analysis.Timezone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timezone %q: %w", *analysis.Timezone, err)
+		}
+		loc = l
+	}
+	from = from.In(loc)
+
+	if analysis.CronExpression != nil && *analysis.CronExpression != "" {
+		schedule, err := cronParser.Parse(*analysis.CronExpression)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid cron expression %q: %w", *analysis.CronExpression, err)
+		}
+		return schedule.Next(from), nil
+	}
+
+	scheduleType := "daily"
+	if analysis.ScheduleType != nil && *analysis.ScheduleType != "" {
+		scheduleType = *analysis.ScheduleType
+	}
+
+	switch {
+	case scheduleType == "hourly":
+		return from.Add(time.Hour), nil
+	case scheduleType == "daily":
+		return from.Add(24 * time.Hour), nil
+	case scheduleType == "weekly":
+		return from.Add(7 * 24 * time.Hour), nil
+	case scheduleType == "monthly":
+		return from.AddDate(0, 1, 0), nil
+	case strings.HasPrefix(scheduleType, "interval:"):
+		d, err := time.ParseDuration(strings.TrimPrefix(scheduleType, "interval:"))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid interval schedule %q: %w", scheduleType, err)
+		}
+		return from.Add(d), nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown schedule_type %q", scheduleType)
+	}
+}
+
+// ValidateSchedule checks that an analysis' schedule configuration (cron
+// expression, schedule_type keyword or timezone) is well-formed. This
+// service doesn't own analysis creation, so it can't enforce this up front
+// itself; it's used here when a dead-lettered row is manually requeued
+// (handleRequeue) and exposed over HTTP (handleValidateSchedule) so the
+// service that does own creation/editing can reject a bad cron expression
+// before ever writing the row.
+func ValidateSchedule(analysis ScheduledAnalysis) error {
+	_, err := calculateNextRun(analysis, time.Now())
+	return err
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestCalculateNextRunScheduleTypes(t *testing.T) {
+	from := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name         string
+		scheduleType *string
+		want         time.Time
+	}{
+		{"nil defaults to daily", nil, from.Add(24 * time.Hour)},
+		{"hourly", strPtr("hourly"), from.Add(time.Hour)},
+		{"daily", strPtr("daily"), from.Add(24 * time.Hour)},
+		{"weekly", strPtr("weekly"), from.Add(7 * 24 * time.Hour)},
+		{"monthly", strPtr("monthly"), from.AddDate(0, 1, 0)},
+		{"interval", strPtr("interval:90m"), from.Add(90 * time.Minute)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			analysis := ScheduledAnalysis{ScheduleType: c.scheduleType}
+			got, err := calculateNextRun(analysis, from)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(c.want) {
+				t.Errorf("got %s, want %s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCalculateNextRunCronExpression(t *testing.T) {
+	from := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	analysis := ScheduledAnalysis{CronExpression: strPtr("0 0 * * *")}
+
+	got, err := calculateNextRun(analysis, from)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestCalculateNextRunTimezone(t *testing.T) {
+	from := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	analysis := ScheduledAnalysis{
+		ScheduleType: strPtr("daily"),
+		Timezone:     strPtr("America/New_York"),
+	}
+
+	got, err := calculateNextRun(analysis, from)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Location().String() != "America/New_York" {
+		t.Errorf("expected result in America/New_York, got %s", got.Location())
+	}
+}
+
+func TestCalculateNextRunInvalidInputs(t *testing.T) {
+	from := time.Now()
+
+	cases := []struct {
+		name     string
+		analysis ScheduledAnalysis
+	}{
+		{"invalid cron expression", ScheduledAnalysis{CronExpression: strPtr("not a cron")}},
+		{"invalid timezone", ScheduledAnalysis{Timezone: strPtr("Nowhere/Fake")}},
+		{"invalid interval duration", ScheduledAnalysis{ScheduleType: strPtr("interval:banana")}},
+		{"unknown schedule type", ScheduledAnalysis{ScheduleType: strPtr("fortnightly")}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := calculateNextRun(c.analysis, from); err == nil {
+				t.Errorf("expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	if err := ValidateSchedule(ScheduledAnalysis{ScheduleType: strPtr("daily")}); err != nil {
+		t.Errorf("expected valid schedule, got error: %v", err)
+	}
+
+	if err := ValidateSchedule(ScheduledAnalysis{CronExpression: strPtr("not a cron")}); err == nil {
+		t.Error("expected invalid cron expression to be rejected")
+	}
+}
@@ -7,35 +7,43 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/CodeClarityCE/utility-types/boilerplates"
-	"github.com/robfig/cron/v3"
 	"github.com/uptrace/bun"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 type ScheduledAnalysis struct {
-	bun.BaseModel    `bun:"table:analysis"`
-	ID               string                 `bun:"id,pk"`
-	CreatedOn        time.Time              `bun:"created_on"`
-	Config           map[string]interface{} `bun:"config,type:jsonb"`
-	Stage            int                    `bun:"stage"`
-	Status           string                 `bun:"status"`
-	Steps            interface{}            `bun:"steps,type:jsonb"`
-	StartedOn        *time.Time             `bun:"started_on"`
-	EndedOn          *time.Time             `bun:"ended_on"`
-	Branch           string                 `bun:"branch"`
-	Tag              *string                `bun:"tag"`
-	CommitHash       *string                `bun:"commit_hash"`
-	ScheduleType     *string                `bun:"schedule_type"`
-	NextScheduledRun *time.Time             `bun:"next_scheduled_run"`
-	IsActive         bool                   `bun:"is_active"`
-	LastScheduledRun *time.Time             `bun:"last_scheduled_run"`
-	ProjectID        string                 `bun:"projectId"`
-	AnalyzerID       string                 `bun:"analyzerId"`
-	OrganizationID   string                 `bun:"organizationId"`
-	IntegrationID    *string                `bun:"integrationId"`
-	CreatedByID      string                 `bun:"createdById"`
+	bun.BaseModel      `bun:"table:analysis"`
+	ID                 string                 `bun:"id,pk"`
+	CreatedOn          time.Time              `bun:"created_on"`
+	Config             map[string]interface{} `bun:"config,type:jsonb"`
+	Stage              int                    `bun:"stage"`
+	Status             string                 `bun:"status"`
+	Steps              interface{}            `bun:"steps,type:jsonb"`
+	StartedOn          *time.Time             `bun:"started_on"`
+	EndedOn            *time.Time             `bun:"ended_on"`
+	Branch             string                 `bun:"branch"`
+	Tag                *string                `bun:"tag"`
+	CommitHash         *string                `bun:"commit_hash"`
+	ScheduleType       *string                `bun:"schedule_type"`
+	CronExpression     *string                `bun:"cron_expression"`
+	Timezone           *string                `bun:"timezone"`
+	NextScheduledRun   *time.Time             `bun:"next_scheduled_run"`
+	IsActive           bool                   `bun:"is_active"`
+	LastScheduledRun   *time.Time             `bun:"last_scheduled_run"`
+	DeadLetterReason   *string                `bun:"dead_letter_reason"`
+	DeadLetteredAt     *time.Time             `bun:"dead_lettered_at"`
+	PendingExecutionID *string                `bun:"pending_execution_id"`
+	ProjectID          string                 `bun:"projectId"`
+	AnalyzerID         string                 `bun:"analyzerId"`
+	OrganizationID     string                 `bun:"organizationId"`
+	IntegrationID      *string                `bun:"integrationId"`
+	CreatedByID        string                 `bun:"createdById"`
 }
 
 func (ScheduledAnalysis) TableName() string {
@@ -45,8 +53,11 @@ func (ScheduledAnalysis) TableName() string {
 // SchedulerService wraps the ServiceBase with scheduler-specific functionality
 type SchedulerService struct {
 	*boilerplates.ServiceBase
-	cron   *cron.Cron
-	apiURL string
+	apiURL     string
+	acquirer   *Acquirer
+	acquirerMu sync.Mutex
+	dispatcher *RunDispatcher
+	leader     *LeaderElector
 }
 
 // CreateSchedulerService creates a new SchedulerService
@@ -56,12 +67,8 @@ func CreateSchedulerService() (*SchedulerService, error) {
 		return nil, err
 	}
 
-	// Create cron scheduler
-	c := cron.New(cron.WithSeconds())
-
 	service := &SchedulerService{
 		ServiceBase: base,
-		cron:        c,
 		apiURL:      "http://api:3000", // API connection for creating new analysis executions
 	}
 
@@ -71,13 +78,59 @@ func CreateSchedulerService() (*SchedulerService, error) {
 func (s *SchedulerService) Start() {
 	log.Println("Starting scheduler service...")
 
-	// Add cron job to check for due analyses every minute
-	_, err := s.cron.AddFunc("0 * * * * *", s.processDueAnalyses)
+	ctx := context.Background()
+
+	if err := installNotifyTriggers(ctx, s.DB.CodeClarity); err != nil {
+		log.Printf("Failed to install analysis notify triggers, falling back to full sweeps only: %v", err)
+	}
+
+	dsn, err := buildListenDSN()
 	if err != nil {
-		log.Fatalf("Failed to add cron job: %v", err)
+		log.Fatalf("Failed to build LISTEN connection string: %v", err)
 	}
 
-	s.cron.Start()
+	if _, err := initTracing(ctx); err != nil {
+		log.Printf("Failed to initialize tracing: %v", err)
+	}
+	go serveMetrics(":9090")
+
+	s.dispatcher = NewRunDispatcher(s)
+	go func() {
+		if err := s.dispatcher.ListenHTTP(":8090"); err != nil {
+			log.Printf("Attempt API server stopped: %v", err)
+		}
+	}()
+
+	// Only the elected leader actually acquires and dispatches due
+	// analyses; standby replicas keep retrying the advisory lock so they
+	// can take over the moment the leader's lease connection dies.
+	s.leader = NewLeaderElector(s,
+		func(leadCtx context.Context) {
+			acquirer := NewAcquirer(s, dsn)
+
+			s.acquirerMu.Lock()
+			s.acquirer = acquirer
+			s.acquirerMu.Unlock()
+
+			if err := acquirer.Start(leadCtx); err != nil {
+				log.Printf("Failed to start acquirer after election: %v", err)
+			}
+		},
+		func() {
+			s.acquirerMu.Lock()
+			acquirer := s.acquirer
+			s.acquirer = nil
+			s.acquirerMu.Unlock()
+
+			if acquirer != nil {
+				acquirer.Close()
+			}
+		},
+	)
+	go s.leader.Run(ctx)
+
+	go s.serveHealthz(":8091")
+
 	log.Println("Scheduler service started successfully")
 
 	// Keep the service running
@@ -87,74 +140,84 @@ func (s *SchedulerService) Start() {
 func (s *SchedulerService) processDueAnalyses() {
 	log.Println("Checking for due scheduled analyses...")
 
-	ctx := context.Background()
+	ctx, span := tracer.Start(context.Background(), "scheduler.process_due_analyses")
+	defer span.End()
+
 	var analyses []ScheduledAnalysis
 
-	// Find all due analyses
+	// Find all due analyses. A row is eligible either through one of the
+	// well-known schedule_type values or through a cron_expression; both
+	// funnel into calculateNextRun, where cron_expression takes precedence
+	// when a row has both.
 	err := s.DB.CodeClarity.NewSelect().
 		Model(&analyses).
 		Where("is_active = ?", true).
-		Where("schedule_type IN (?)", bun.In([]string{"daily", "weekly"})).
 		Where("next_scheduled_run <= ?", time.Now()).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.
+				WhereOr("schedule_type IN (?)", bun.In([]string{"hourly", "daily", "weekly", "monthly"})).
+				WhereOr("schedule_type LIKE ?", "interval:%").
+				WhereOr("cron_expression IS NOT NULL")
+		}).
 		Scan(ctx)
 
 	if err != nil {
+		span.RecordError(err)
 		log.Printf("Error fetching due analyses: %v", err)
 		return
 	}
 
 	log.Printf("Found %d due analyses", len(analyses))
+	span.SetAttributes(attribute.Int("due_count", len(analyses)))
+	dueBacklog.Set(float64(len(analyses)))
+
+	if count, err := s.DB.CodeClarity.NewSelect().Model((*ScheduledAnalysis)(nil)).Where("is_active = ?", true).Count(ctx); err == nil {
+		activeSchedules.Set(float64(count))
+	}
 
 	for _, analysis := range analyses {
-		s.processAnalysis(analysis)
+		s.processAnalysis(ctx, analysis)
 	}
 }
 
-func (s *SchedulerService) processAnalysis(analysis ScheduledAnalysis) {
-	log.Printf("Processing scheduled analysis: %s", analysis.ID)
-
-	// Create a new analysis execution to preserve historical results
-	newAnalysisId, err := s.createAnalysisExecution(analysis)
-	if err != nil {
-		log.Printf("Failed to create new analysis execution for %s: %v", analysis.ID, err)
-		return
+// processAnalysis hands the due analysis off to the RunDispatcher, which
+// records the attempt and takes care of retries, backoff and
+// dead-lettering; see dispatcher.go. ctx carries the trace started by
+// processDueAnalyses so the dispatch span is its child.
+func (s *SchedulerService) processAnalysis(ctx context.Context, analysis ScheduledAnalysis) {
+	ctx, span := tracer.Start(ctx, "scheduler.process_analysis")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("analysis.id", analysis.ID),
+		attribute.String("project.id", analysis.ProjectID),
+		attribute.String("organization.id", analysis.OrganizationID),
+	)
+	if analysis.ScheduleType != nil {
+		span.SetAttributes(attribute.String("schedule_type", *analysis.ScheduleType))
 	}
-
-	log.Printf("Created new analysis execution: %s for scheduled analysis: %s", newAnalysisId, analysis.ID)
-
-	// Send message to RabbitMQ to trigger the new analysis execution
-	err = s.sendAnalysisMessage(analysis, newAnalysisId)
-	if err != nil {
-		log.Printf("Failed to send analysis message for %s: %v", newAnalysisId, err)
-		return
+	if analysis.NextScheduledRun != nil {
+		dispatchLag.Observe(time.Since(*analysis.NextScheduledRun).Seconds())
 	}
 
-	// Update last run time and calculate next run time for the original scheduled analysis
-	ctx := context.Background()
-	now := time.Now()
-	nextRun := s.calculateNextRun(analysis.ScheduleType, now)
-
-	_, err = s.DB.CodeClarity.NewUpdate().
-		Model((*ScheduledAnalysis)(nil)).
-		Set("last_scheduled_run = ?", now).
-		Set("next_scheduled_run = ?", nextRun).
-		Where("id = ?", analysis.ID).
-		Exec(ctx)
-
-	if err != nil {
-		log.Printf("Failed to update analysis schedule for %s: %v", analysis.ID, err)
-		return
-	}
-
-	log.Printf("Successfully processed analysis %s, new execution: %s, next run: %s", analysis.ID, newAnalysisId, nextRun.Format(time.RFC3339))
+	log.Printf("Processing scheduled analysis: %s", analysis.ID)
+	s.dispatcher.Dispatch(ctx, analysis)
 }
 
-func (s *SchedulerService) createAnalysisExecution(analysis ScheduledAnalysis) (string, error) {
+func (s *SchedulerService) createAnalysisExecution(ctx context.Context, analysis ScheduledAnalysis) (string, error) {
 	// Call the API to create a new analysis execution
 	url := fmt.Sprintf("%s/org/%s/projects/%s/analyses/%s/execute",
 		s.apiURL, analysis.OrganizationID, analysis.ProjectID, analysis.ID)
 
-	resp, err := http.Post(url, "application/json", nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Propagate the trace so the API and downstream plugins join this span.
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("failed to call API: %v", err)
 	}
@@ -175,7 +238,33 @@ func (s *SchedulerService) createAnalysisExecution(analysis ScheduledAnalysis) (
 	return result.ID, nil
 }
 
-func (s *SchedulerService) sendAnalysisMessage(analysis ScheduledAnalysis, newAnalysisId string) error {
+// serveHealthz exposes liveness and leadership status for the orchestrator's
+// probes: /healthz always reports ok as long as the process is up, while
+// /leader reports whether this replica is the one actually dispatching
+// analyses, so a readiness probe can route scheduling traffic accordingly.
+func (s *SchedulerService) serveHealthz(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/leader", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]bool{"leader": s.leader.IsLeader()})
+	})
+
+	log.Printf("Serving healthz/leader endpoints on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("Healthz server stopped: %v", err)
+	}
+}
+
+func (s *SchedulerService) sendAnalysisMessage(ctx context.Context, analysis ScheduledAnalysis, newAnalysisId string) error {
+	// Propagate the trace into the message headers so plugins consuming it
+	// off the queue can join the same trace as the dispatch that sent it.
+	traceHeaders := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, traceHeaders)
+
 	// Create message in the format expected by dispatcher
 	message := map[string]interface{}{
 		"analysis_id":     newAnalysisId,
@@ -183,6 +272,7 @@ func (s *SchedulerService) sendAnalysisMessage(analysis ScheduledAnalysis, newAn
 		"integration_id":  analysis.IntegrationID,
 		"organization_id": analysis.OrganizationID,
 		"config":          analysis.Config,
+		"trace_headers":   traceHeaders,
 	}
 
 	body, err := json.Marshal(message)
@@ -194,21 +284,6 @@ func (s *SchedulerService) sendAnalysisMessage(analysis ScheduledAnalysis, newAn
 	return s.SendMessage("api_request", body)
 }
 
-func (s *SchedulerService) calculateNextRun(scheduleType *string, from time.Time) time.Time {
-	if scheduleType == nil {
-		return from.Add(24 * time.Hour) // default to daily
-	}
-
-	switch *scheduleType {
-	case "daily":
-		return from.Add(24 * time.Hour)
-	case "weekly":
-		return from.Add(7 * 24 * time.Hour)
-	default:
-		return from.Add(24 * time.Hour) // default to daily
-	}
-}
-
 func main() {
 	service, err := CreateSchedulerService()
 	if err != nil {
@@ -30,11 +30,6 @@ func TestCreateSchedulerService(t *testing.T) {
 		t.Error("Expected ServiceBase to be embedded, got nil")
 	}
 	
-	// Test that cron scheduler is initialized
-	if service.cron == nil {
-		t.Error("Expected cron scheduler to be initialized, got nil")
-	}
-	
 	// Test that API URL is set
 	if service.apiURL == "" {
 		t.Error("Expected API URL to be set, got empty string")